@@ -0,0 +1,184 @@
+// Copyright 2016 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package chunks
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/attic-labs/noms/go/hash"
+)
+
+// EvictionPolicy selects which chunk a bounded MemoryStorage reclaims first
+// once it's over its byte budget.
+type EvictionPolicy int
+
+const (
+	// LRU evicts the least-recently-used chunk; both a read and the first
+	// write of a chunk count as a use.
+	LRU EvictionPolicy = iota
+	// LFU evicts the least-frequently-read chunk.
+	LFU
+)
+
+// NewBoundedMemoryStorage returns a MemoryStorage that caps the total size of
+// the chunks it holds at maxBytes, evicting under policy once a PutAll would
+// push it over budget. This turns MemoryStorage into a usable front-cache for
+// a slower, authoritative ChunkStore (e.g. a remote store), rather than only
+// ever an unbounded test fixture.
+//
+// Eviction only ever reclaims chunks that haven't been passed to Pin -- the
+// caller is expected to Pin every hash reachable from the chunk(s) it cares
+// about keeping (typically the current root chain) before calling PutAll, so
+// that a GC sweep or a cold cache warm-up can't evict something still live.
+//
+// Pins don't expire on their own: as the root chain moves on, hashes pinned
+// for a prior generation stay ineligible for eviction forever unless the
+// caller calls Unpin on them, which will gradually push a long-lived store
+// back toward unbounded growth. A caller that re-pins each new root chain
+// should Unpin the previous one's hashes first.
+func NewBoundedMemoryStorage(maxBytes int64, policy EvictionPolicy) *MemoryStorage {
+	return &MemoryStorage{
+		eviction: &evictionState{
+			policy:   policy,
+			maxBytes: maxBytes,
+			recency:  list.New(),
+			elems:    map[hash.Hash]*list.Element{},
+			freq:     map[hash.Hash]int64{},
+			pinned:   hash.HashSet{},
+		},
+	}
+}
+
+// Pin marks h as ineligible for eviction until Unpin is called on h or the
+// store is rebuilt. Callers doing GC or replication should Pin every hash
+// reachable from the root chain they want to preserve, and should Unpin a
+// generation's hashes once a newer root chain supersedes it -- Pin has no
+// expiry of its own, so a hash left pinned stays unevictable indefinitely.
+func (ms *MemoryStorage) Pin(h hash.Hash) {
+	if ms.eviction == nil {
+		return
+	}
+	ms.eviction.mu.Lock()
+	defer ms.eviction.mu.Unlock()
+	ms.eviction.pinned.Insert(h)
+}
+
+// Unpin reverses a prior Pin, making h eligible for eviction again (subject
+// to the configured policy, same as any other entry). Unpinning a hash that
+// was never pinned, or that's already been evicted, is a no-op.
+func (ms *MemoryStorage) Unpin(h hash.Hash) {
+	if ms.eviction == nil {
+		return
+	}
+	ms.eviction.mu.Lock()
+	defer ms.eviction.mu.Unlock()
+	ms.eviction.pinned.Remove(h)
+}
+
+// Stats returns the current size of a bounded MemoryStorage in bytes and
+// chunks, along with cumulative Get/Has hit and miss counts. It returns all
+// zeroes for a MemoryStorage not created via NewBoundedMemoryStorage.
+func (ms *MemoryStorage) Stats() (bytes, chunks, hits, misses int64) {
+	if ms.eviction == nil {
+		return 0, 0, 0, 0
+	}
+	ms.eviction.mu.Lock()
+	defer ms.eviction.mu.Unlock()
+	e := ms.eviction
+	return e.bytes, int64(e.recency.Len()), e.hits, e.misses
+}
+
+// evictionState tracks the recency/frequency list and byte budget for a
+// bounded MemoryStorage. It's guarded by its own mutex, independent of
+// MemoryStorage.mu, because Get/Has only need a read lock on ms.data but
+// still need to mutate the recency list on every call.
+type evictionState struct {
+	mu sync.Mutex
+
+	policy   EvictionPolicy
+	maxBytes int64
+	bytes    int64
+	hits     int64
+	misses   int64
+
+	recency *list.List // list.Element.Value is a hash.Hash; front is most recently touched
+	elems   map[hash.Hash]*list.Element
+	freq    map[hash.Hash]int64 // read counts, consulted only under LFU
+
+	pinned hash.HashSet
+}
+
+// touch records a hit against h, promoting it under the configured policy.
+func (e *evictionState) touch(h hash.Hash) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.hits++
+	e.freq[h]++
+	if elem, ok := e.elems[h]; ok && e.policy == LRU {
+		e.recency.MoveToFront(elem)
+	}
+}
+
+func (e *evictionState) miss() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.misses++
+}
+
+// put records newly-written chunks and evicts unpinned entries from data
+// until bytes is back under maxBytes.
+func (e *evictionState) put(chunks map[hash.Hash]Chunk, data map[hash.Hash]Chunk) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for h, c := range chunks {
+		if _, ok := e.elems[h]; ok {
+			continue
+		}
+		e.elems[h] = e.recency.PushFront(h)
+		e.freq[h] = 1
+		e.bytes += int64(len(c.Data()))
+	}
+
+	for e.bytes > e.maxBytes {
+		victim := e.evictionCandidate()
+		if victim == nil {
+			break
+		}
+		h := victim.Value.(hash.Hash)
+		e.recency.Remove(victim)
+		delete(e.elems, h)
+		delete(e.freq, h)
+		e.bytes -= int64(len(data[h].Data()))
+		delete(data, h)
+	}
+}
+
+// evictionCandidate returns the least valuable unpinned entry under the
+// configured policy, or nil if every remaining entry is pinned.
+func (e *evictionState) evictionCandidate() *list.Element {
+	if e.policy == LRU {
+		for elem := e.recency.Back(); elem != nil; elem = elem.Prev() {
+			if !e.pinned.Has(elem.Value.(hash.Hash)) {
+				return elem
+			}
+		}
+		return nil
+	}
+
+	var best *list.Element
+	var bestFreq int64
+	for elem := e.recency.Back(); elem != nil; elem = elem.Prev() {
+		h := elem.Value.(hash.Hash)
+		if e.pinned.Has(h) {
+			continue
+		}
+		if best == nil || e.freq[h] < bestFreq {
+			best, bestFreq = elem, e.freq[h]
+		}
+	}
+	return best
+}
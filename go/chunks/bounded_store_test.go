@@ -0,0 +1,202 @@
+// Copyright 2016 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package chunks
+
+import (
+	"testing"
+
+	"github.com/attic-labs/noms/go/hash"
+)
+
+func TestBoundedMemoryStorageLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	storage := NewBoundedMemoryStorage(8, LRU)
+	a, b, c := NewChunk([]byte("aaaa")), NewChunk([]byte("bbbb")), NewChunk([]byte("cccc"))
+
+	put := func(ch Chunk) {
+		if err := storage.PutAll(map[hash.Hash]Chunk{ch.Hash(): ch}); err != nil {
+			t.Fatalf("PutAll: %v", err)
+		}
+	}
+
+	put(a)
+	put(b)
+	// Touch a so it's more recently used than b when c forces an eviction.
+	storage.Get(a.Hash())
+	put(c)
+
+	if storage.Has(b.Hash()) {
+		t.Fatalf("expected b to be evicted as the least recently used entry")
+	}
+	if !storage.Has(a.Hash()) || !storage.Has(c.Hash()) {
+		t.Fatalf("expected a and c to survive eviction")
+	}
+}
+
+func TestBoundedMemoryStorageLFUEvictsLeastFrequentlyUsed(t *testing.T) {
+	storage := NewBoundedMemoryStorage(8, LFU)
+	a, b, c := NewChunk([]byte("aaaa")), NewChunk([]byte("bbbb")), NewChunk([]byte("cccc"))
+
+	put := func(ch Chunk) {
+		if err := storage.PutAll(map[hash.Hash]Chunk{ch.Hash(): ch}); err != nil {
+			t.Fatalf("PutAll: %v", err)
+		}
+	}
+
+	put(a)
+	put(b)
+	// a is read again, raising its frequency above b's (and c's, once added).
+	storage.Get(a.Hash())
+	put(c)
+
+	if storage.Has(b.Hash()) {
+		t.Fatalf("expected b to be evicted as the least frequently used entry")
+	}
+	if !storage.Has(a.Hash()) || !storage.Has(c.Hash()) {
+		t.Fatalf("expected a and c to survive eviction")
+	}
+}
+
+func TestBoundedMemoryStoragePinExemptsFromEviction(t *testing.T) {
+	storage := NewBoundedMemoryStorage(4, LRU)
+	a, b := NewChunk([]byte("aaaa")), NewChunk([]byte("bbbb"))
+
+	if err := storage.PutAll(map[hash.Hash]Chunk{a.Hash(): a}); err != nil {
+		t.Fatalf("PutAll: %v", err)
+	}
+	storage.Pin(a.Hash())
+
+	if err := storage.PutAll(map[hash.Hash]Chunk{b.Hash(): b}); err != nil {
+		t.Fatalf("PutAll: %v", err)
+	}
+
+	if !storage.Has(a.Hash()) {
+		t.Fatalf("pinned chunk a should never be evicted")
+	}
+	if storage.Has(b.Hash()) {
+		t.Fatalf("expected b to be evicted, since a is pinned and b is the only unpinned entry")
+	}
+}
+
+func TestBoundedMemoryStorageUnpinReenablesEviction(t *testing.T) {
+	storage := NewBoundedMemoryStorage(4, LRU)
+	a, b := NewChunk([]byte("aaaa")), NewChunk([]byte("bbbb"))
+
+	if err := storage.PutAll(map[hash.Hash]Chunk{a.Hash(): a}); err != nil {
+		t.Fatalf("PutAll: %v", err)
+	}
+	storage.Pin(a.Hash())
+	storage.Unpin(a.Hash())
+
+	if err := storage.PutAll(map[hash.Hash]Chunk{b.Hash(): b}); err != nil {
+		t.Fatalf("PutAll: %v", err)
+	}
+
+	if storage.Has(a.Hash()) {
+		t.Fatalf("expected a to be evicted once unpinned, freeing room for b")
+	}
+	if !storage.Has(b.Hash()) {
+		t.Fatalf("expected b to be retained")
+	}
+}
+
+func TestBoundedMemoryStorageStats(t *testing.T) {
+	storage := NewBoundedMemoryStorage(1<<20, LRU)
+	a := NewChunk([]byte("aaaa"))
+	if err := storage.PutAll(map[hash.Hash]Chunk{a.Hash(): a}); err != nil {
+		t.Fatalf("PutAll: %v", err)
+	}
+
+	storage.Get(a.Hash())                        // hit
+	storage.Get(NewChunk([]byte("gone")).Hash()) // miss
+
+	bytes, chunks, hits, misses := storage.Stats()
+	if bytes != 4 {
+		t.Fatalf("expected 4 bytes, got %d", bytes)
+	}
+	if chunks != 1 {
+		t.Fatalf("expected 1 chunk, got %d", chunks)
+	}
+	if hits != 1 {
+		t.Fatalf("expected 1 hit, got %d", hits)
+	}
+	if misses != 1 {
+		t.Fatalf("expected 1 miss, got %d", misses)
+	}
+}
+
+func TestBoundedMemoryStorageHasChunksPromotesEntries(t *testing.T) {
+	storage := NewBoundedMemoryStorage(8, LRU)
+	a, b, c := NewChunk([]byte("aaaa")), NewChunk([]byte("bbbb")), NewChunk([]byte("cccc"))
+
+	put := func(ch Chunk) {
+		if err := storage.PutAll(map[hash.Hash]Chunk{ch.Hash(): ch}); err != nil {
+			t.Fatalf("PutAll: %v", err)
+		}
+	}
+	put(a)
+	put(b)
+
+	// Promote a via HasChunks rather than Get, then force an eviction: if
+	// HasChunks didn't record the touch, b (untouched since insertion) would
+	// incorrectly survive over a.
+	hashes := hash.HashSet{}
+	hashes.Insert(a.Hash())
+	storage.HasChunks(hashes)
+
+	put(c)
+
+	if storage.Has(b.Hash()) {
+		t.Fatalf("expected b to be evicted; HasChunks should have promoted a instead")
+	}
+	if !storage.Has(a.Hash()) {
+		t.Fatalf("expected a, promoted via HasChunks, to survive eviction")
+	}
+
+	_, _, hits, _ := storage.Stats()
+	if hits < 1 {
+		t.Fatalf("expected HasChunks to record at least one hit, got %d", hits)
+	}
+}
+
+func TestBoundedMemoryStorageIterateDoesNotPromote(t *testing.T) {
+	storage := NewBoundedMemoryStorage(8, LRU)
+	a, b, c := NewChunk([]byte("aaaa")), NewChunk([]byte("bbbb")), NewChunk([]byte("cccc"))
+
+	put := func(ch Chunk) {
+		if err := storage.PutAll(map[hash.Hash]Chunk{ch.Hash(): ch}); err != nil {
+			t.Fatalf("PutAll: %v", err)
+		}
+	}
+	put(a)
+	put(b)
+
+	// A full Iterate scan -- the GC/replication-diff use case -- must not
+	// promote what it visits; only a (promoted via Get) should survive.
+	storage.Get(a.Hash())
+	visited := 0
+	if err := storage.Iterate(func(h hash.Hash, ch Chunk) bool {
+		visited++
+		return false
+	}); err != nil {
+		t.Fatalf("Iterate: %v", err)
+	}
+	if visited != 2 {
+		t.Fatalf("expected to visit 2 chunks, visited %d", visited)
+	}
+
+	_, _, hits, _ := storage.Stats()
+	if hits != 1 {
+		t.Fatalf("expected exactly 1 hit (from Get, not from Iterate), got %d", hits)
+	}
+
+	put(c)
+
+	if storage.Has(b.Hash()) {
+		t.Fatalf("expected b to be evicted; Iterate must not have promoted it")
+	}
+	if !storage.Has(a.Hash()) {
+		t.Fatalf("expected a, promoted via Get (not Iterate), to survive eviction")
+	}
+}
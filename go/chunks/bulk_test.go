@@ -0,0 +1,133 @@
+// Copyright 2016 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package chunks
+
+import (
+	"testing"
+
+	"github.com/attic-labs/noms/go/hash"
+)
+
+func TestMemoryStorageHasChunksSplitsPresentAndMissing(t *testing.T) {
+	storage := &MemoryStorage{}
+	present := NewChunk([]byte("present"))
+	if err := storage.PutAll(map[hash.Hash]Chunk{present.Hash(): present}); err != nil {
+		t.Fatalf("PutAll: %v", err)
+	}
+	missing := NewChunk([]byte("missing")).Hash()
+
+	hashes := hash.HashSet{}
+	hashes.Insert(present.Hash())
+	hashes.Insert(missing)
+
+	gotPresent, gotMissing := storage.HasChunks(hashes)
+	if !gotPresent.Has(present.Hash()) || gotPresent.Has(missing) {
+		t.Fatalf("present set wrong: %v", gotPresent)
+	}
+	if !gotMissing.Has(missing) || gotMissing.Has(present.Hash()) {
+		t.Fatalf("missing set wrong: %v", gotMissing)
+	}
+}
+
+func TestMemoryStorageIterateEarlyTermination(t *testing.T) {
+	storage := &MemoryStorage{}
+	cs := []Chunk{NewChunk([]byte("a")), NewChunk([]byte("b")), NewChunk([]byte("c"))}
+	pending := map[hash.Hash]Chunk{}
+	for _, c := range cs {
+		pending[c.Hash()] = c
+	}
+	if err := storage.PutAll(pending); err != nil {
+		t.Fatalf("PutAll: %v", err)
+	}
+
+	visited := 0
+	err := storage.Iterate(func(h hash.Hash, c Chunk) bool {
+		visited++
+		return true // stop after the first callback
+	})
+	if err != nil {
+		t.Fatalf("Iterate: %v", err)
+	}
+	if visited != 1 {
+		t.Fatalf("expected Iterate to stop after one callback, visited %d", visited)
+	}
+}
+
+func TestMemoryStorageIterateVisitsEveryChunk(t *testing.T) {
+	storage := &MemoryStorage{}
+	cs := []Chunk{NewChunk([]byte("a")), NewChunk([]byte("b")), NewChunk([]byte("c"))}
+	pending := map[hash.Hash]Chunk{}
+	for _, c := range cs {
+		pending[c.Hash()] = c
+	}
+	if err := storage.PutAll(pending); err != nil {
+		t.Fatalf("PutAll: %v", err)
+	}
+
+	seen := hash.HashSet{}
+	err := storage.Iterate(func(h hash.Hash, c Chunk) bool {
+		seen.Insert(h)
+		return false
+	})
+	if err != nil {
+		t.Fatalf("Iterate: %v", err)
+	}
+	if len(seen) != len(cs) {
+		t.Fatalf("expected to visit %d chunks, saw %d", len(cs), len(seen))
+	}
+}
+
+func TestMemoryStoreViewHasChunksMergesPendingAndStorage(t *testing.T) {
+	storage := &MemoryStorage{}
+	persisted := NewChunk([]byte("persisted"))
+	if err := storage.PutAll(map[hash.Hash]Chunk{persisted.Hash(): persisted}); err != nil {
+		t.Fatalf("PutAll: %v", err)
+	}
+
+	view := storage.NewView().(*MemoryStoreView)
+	pending := NewChunk([]byte("pending"))
+	if err := view.Put(pending); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	hashes := hash.HashSet{}
+	hashes.Insert(persisted.Hash())
+	hashes.Insert(pending.Hash())
+
+	present, missing := view.HasChunks(hashes)
+	if !present.Has(persisted.Hash()) || !present.Has(pending.Hash()) {
+		t.Fatalf("expected both persisted and pending hashes present, got %v", present)
+	}
+	if len(missing) != 0 {
+		t.Fatalf("expected no missing hashes, got %v", missing)
+	}
+}
+
+func TestMemoryStoreViewIterateSkipsStorageShadowedByPending(t *testing.T) {
+	storage := &MemoryStorage{}
+	c := NewChunk([]byte("shared-hash-content"))
+	if err := storage.PutAll(map[hash.Hash]Chunk{c.Hash(): c}); err != nil {
+		t.Fatalf("PutAll: %v", err)
+	}
+
+	view := storage.NewView().(*MemoryStoreView)
+	// Re-staging the same chunk in pending must only be visited once by
+	// Iterate, not once from pending and again from storage.
+	if err := view.Put(c); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	visits := 0
+	err := view.Iterate(func(h hash.Hash, got Chunk) bool {
+		visits++
+		return false
+	})
+	if err != nil {
+		t.Fatalf("Iterate: %v", err)
+	}
+	if visits != 1 {
+		t.Fatalf("expected exactly one visit for the shared hash, got %d", visits)
+	}
+}
@@ -21,6 +21,21 @@ type MemoryStorage struct {
 	data     map[hash.Hash]Chunk
 	rootHash hash.Hash
 	mu       sync.RWMutex
+
+	// eviction is nil for a plain, unbounded MemoryStorage. It's populated by
+	// NewBoundedMemoryStorage to cap ms.data at a byte budget; see
+	// bounded_store.go.
+	eviction *evictionState
+
+	// VerifyOnWrite, if set, makes Put/PutMany/PutAll recompute each chunk's
+	// hash from its content and return a VerificationError on mismatch,
+	// instead of trusting Chunk.Hash() blindly. VerifyOnRead does the same on
+	// every Get, where it panics with a VerificationError instead, since Get
+	// has no error to return. Both default to false, so a MemoryStorage built
+	// the old way (zero value, or NewView()'d with no further configuration)
+	// behaves exactly as it always has.
+	VerifyOnWrite bool
+	VerifyOnRead  bool
 }
 
 // NewView vends a MemoryStoreView backed by this MemoryStorage. It's
@@ -35,8 +50,19 @@ func (ms *MemoryStorage) Get(h hash.Hash) Chunk {
 	ms.mu.RLock()
 	defer ms.mu.RUnlock()
 	if c, ok := ms.data[h]; ok {
+		if ms.eviction != nil {
+			ms.eviction.touch(h)
+		}
+		if ms.VerifyOnRead {
+			if err := verifyChunk(c); err != nil {
+				panic(err)
+			}
+		}
 		return c
 	}
+	if ms.eviction != nil {
+		ms.eviction.miss()
+	}
 	return EmptyChunk
 }
 
@@ -46,11 +72,87 @@ func (ms *MemoryStorage) Has(r hash.Hash) bool {
 	ms.mu.RLock()
 	defer ms.mu.RUnlock()
 	_, ok := ms.data[r]
+	if ms.eviction != nil {
+		if ok {
+			ms.eviction.touch(r)
+		} else {
+			ms.eviction.miss()
+		}
+	}
 	return ok
 }
 
-// PutAll adds all of chunks to ms.data.
-func (ms *MemoryStorage) PutAll(chunks map[hash.Hash]Chunk) {
+// HasChunks splits hashes into the subset present in ms.data and the subset
+// that is missing, in a single locked pass. It exists alongside Has/HasMany
+// for callers -- garbage collection, replication diff, debug endpoints --
+// that need both halves of the answer and would otherwise have to either
+// call Has once per hash (re-acquiring the RLock each time) or compute the
+// missing set themselves from HasMany's result.
+//
+// Like Has, a present/missing check against a bounded MemoryStorage promotes
+// each present hash and records a hit or miss, so a bulk caller (its stated
+// use case is GC and replication-diff, which commonly run against a bounded
+// front-cache) can't starve entries it only ever checks through HasChunks
+// out of recency/frequency tracking.
+func (ms *MemoryStorage) HasChunks(hashes hash.HashSet) (present, missing hash.HashSet) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	present, missing = hash.HashSet{}, hash.HashSet{}
+	for h := range hashes {
+		if _, ok := ms.data[h]; ok {
+			present.Insert(h)
+			if ms.eviction != nil {
+				ms.eviction.touch(h)
+			}
+		} else {
+			missing.Insert(h)
+			if ms.eviction != nil {
+				ms.eviction.miss()
+			}
+		}
+	}
+	return
+}
+
+// Iterate calls cb with every chunk in ms.data, stopping early if cb returns
+// true. It holds ms's read lock for its entire duration, so it sees a
+// point-in-time view of ms.data: a concurrent PutAll is simply serialized
+// against it, either completing before Iterate starts or waiting for it to
+// finish, never interleaving.
+//
+// Unlike Get/Has/HasChunks, visiting a chunk here does not promote it or
+// count as a hit against a bounded MemoryStorage. Iterate's stated callers
+// -- GC and replication-diff -- each make one full pass over every chunk in
+// the store, so treating that pass as a "use" would touch every entry
+// uniformly and erase whatever real recency/frequency signal eviction was
+// tracking, making the next eviction decision no better than arbitrary. A
+// full scan is maintenance, not read traffic.
+func (ms *MemoryStorage) Iterate(cb func(h hash.Hash, c Chunk) bool) error {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	for h, c := range ms.data {
+		if cb(h, c) {
+			break
+		}
+	}
+	return nil
+}
+
+// PutAll adds all of chunks to ms.data. If ms was created with
+// NewBoundedMemoryStorage, it also records each chunk in the recency list and
+// evicts the least valuable unpinned chunks until ms is back under its byte
+// budget. If VerifyOnWrite is set, every chunk is content-verified before any
+// of them are added; PutAll returns the first VerificationError it finds and
+// adds none of chunks.
+func (ms *MemoryStorage) PutAll(chunks map[hash.Hash]Chunk) error {
+	if ms.VerifyOnWrite {
+		for _, c := range chunks {
+			if err := verifyChunk(c); err != nil {
+				return err
+			}
+		}
+	}
+
 	ms.mu.Lock()
 	defer ms.mu.Unlock()
 	if ms.data == nil {
@@ -59,6 +161,10 @@ func (ms *MemoryStorage) PutAll(chunks map[hash.Hash]Chunk) {
 	for h, c := range chunks {
 		ms.data[h] = c
 	}
+	if ms.eviction != nil {
+		ms.eviction.put(chunks, ms.data)
+	}
+	return nil
 }
 
 // Len returns the number of Chunks in ms.data.
@@ -138,23 +244,88 @@ func (ms *MemoryStoreView) HasMany(hashes hash.HashSet) hash.HashSet {
 	return present
 }
 
+// HasChunks splits hashes into the subset present (in ms.pending or
+// ms.storage) and the subset that is missing, checking pending under a
+// single RLock and delegating the rest to storage's own locked pass.
+func (ms *MemoryStoreView) HasChunks(hashes hash.HashSet) (present, missing hash.HashSet) {
+	ms.mu.RLock()
+	rest := hash.HashSet{}
+	present, missing = hash.HashSet{}, hash.HashSet{}
+	for h := range hashes {
+		if _, ok := ms.pending[h]; ok {
+			present.Insert(h)
+		} else {
+			rest.Insert(h)
+		}
+	}
+	ms.mu.RUnlock()
+
+	storagePresent, storageMissing := ms.storage.HasChunks(rest)
+	for h := range storagePresent {
+		present.Insert(h)
+	}
+	for h := range storageMissing {
+		missing.Insert(h)
+	}
+	return
+}
+
+// Iterate calls cb with every chunk visible to ms -- first the pending set,
+// then ms.storage -- stopping early if cb returns true. See
+// MemoryStorage.Iterate for the snapshot semantics of the storage half.
+func (ms *MemoryStoreView) Iterate(cb func(h hash.Hash, c Chunk) bool) error {
+	ms.mu.RLock()
+	pending := make(map[hash.Hash]Chunk, len(ms.pending))
+	for h, c := range ms.pending {
+		pending[h] = c
+	}
+	ms.mu.RUnlock()
+
+	for h, c := range pending {
+		if cb(h, c) {
+			return nil
+		}
+	}
+	return ms.storage.Iterate(func(h hash.Hash, c Chunk) bool {
+		if _, ok := pending[h]; ok {
+			return false
+		}
+		return cb(h, c)
+	})
+}
+
 func (ms *MemoryStoreView) Version() string {
 	return constants.NomsVersion
 }
 
-func (ms *MemoryStoreView) Put(c Chunk) {
+// Put stages c for the next Flush/Commit. If ms.storage.VerifyOnWrite is
+// set, it returns a VerificationError instead of staging c when c's content
+// doesn't hash to c.Hash().
+func (ms *MemoryStoreView) Put(c Chunk) error {
+	if ms.storage.VerifyOnWrite {
+		if err := verifyChunk(c); err != nil {
+			return err
+		}
+	}
+
 	ms.mu.Lock()
 	defer ms.mu.Unlock()
 	if ms.pending == nil {
 		ms.pending = map[hash.Hash]Chunk{}
 	}
 	ms.pending[c.Hash()] = c
+	return nil
 }
 
-func (ms *MemoryStoreView) PutMany(chunks []Chunk) {
+// PutMany stages each of chunks in order, stopping at (and returning) the
+// first error Put returns; chunks before the failing one remain staged.
+func (ms *MemoryStoreView) PutMany(chunks []Chunk) error {
 	for _, c := range chunks {
-		ms.Put(c)
+		if err := ms.Put(c); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
 func (ms *MemoryStoreView) Len() int {
@@ -163,10 +334,15 @@ func (ms *MemoryStoreView) Len() int {
 	return len(ms.pending) + ms.storage.Len()
 }
 
+// Flush pushes ms's pending set into its backing storage. ms.pending was
+// already verified chunk-by-chunk in Put/PutMany if VerifyOnWrite is set, so
+// a PutAll failure here means a chunk was staged while VerifyOnWrite was off
+// and the flag was turned on before Flush; that's an invariant violation,
+// not a recoverable error, hence the panic rather than a return value here.
 func (ms *MemoryStoreView) Flush() {
 	ms.mu.Lock()
 	defer ms.mu.Unlock()
-	ms.storage.PutAll(ms.pending)
+	d.PanicIfError(ms.storage.PutAll(ms.pending))
 	ms.pending = nil
 }
 
@@ -188,7 +364,7 @@ func (ms *MemoryStoreView) Commit(current, last hash.Hash) bool {
 	if last != ms.rootHash {
 		return false
 	}
-	ms.storage.PutAll(ms.pending)
+	d.PanicIfError(ms.storage.PutAll(ms.pending))
 	ms.pending = nil
 
 	success := ms.storage.UpdateRoot(current, last)
@@ -0,0 +1,180 @@
+// Copyright 2016 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package chunks
+
+import (
+	"github.com/attic-labs/noms/go/constants"
+	"github.com/attic-labs/noms/go/d"
+	"github.com/attic-labs/noms/go/hash"
+)
+
+// NewPrivateView vends a ChunkStore backed by this MemoryStorage, just like
+// NewView, except that the returned view keeps no lock around its pending
+// set. Callers get this for free: most pending sets are built up entirely on
+// one goroutine (e.g. a single commit pipeline), and in that case the
+// RWMutex every MemoryStoreView takes on each Put/Get/Has is pure
+// allocation and contention overhead paid for a guarantee nobody needs.
+//
+// A view returned by NewPrivateView MUST be owned by a single goroutine from
+// creation until its Flush or Commit call; it is not safe to share across
+// goroutines or to call concurrently with itself. MemoryStorage itself is
+// unaffected -- the private view still takes ms's lock at Flush/Commit time,
+// the same as a view from NewView would.
+func (ms *MemoryStorage) NewPrivateView() ChunkStore {
+	return &privateStoreView{storage: ms, rootHash: ms.rootHash}
+}
+
+// privateStoreView is the lock-free counterpart to MemoryStoreView. See
+// NewPrivateView for its single-owner contract.
+type privateStoreView struct {
+	pending  map[hash.Hash]Chunk
+	rootHash hash.Hash
+
+	storage *MemoryStorage
+}
+
+func (ms *privateStoreView) Get(h hash.Hash) Chunk {
+	if c, ok := ms.pending[h]; ok {
+		return c
+	}
+	return ms.storage.Get(h)
+}
+
+func (ms *privateStoreView) GetMany(hashes hash.HashSet, foundChunks chan *Chunk) {
+	for h := range hashes {
+		c := ms.Get(h)
+		if !c.IsEmpty() {
+			foundChunks <- &c
+		}
+	}
+	return
+}
+
+func (ms *privateStoreView) Has(h hash.Hash) bool {
+	if _, ok := ms.pending[h]; ok {
+		return true
+	}
+	return ms.storage.Has(h)
+}
+
+func (ms *privateStoreView) HasMany(hashes hash.HashSet) hash.HashSet {
+	present := hash.HashSet{}
+	for h := range hashes {
+		if ms.Has(h) {
+			present.Insert(h)
+		}
+	}
+	return present
+}
+
+// HasChunks splits hashes into the subset present (in ms.pending or
+// ms.storage) and the subset that is missing. See MemoryStoreView.HasChunks;
+// the only difference here is that checking ms.pending takes no lock, per
+// privateStoreView's single-owner contract.
+func (ms *privateStoreView) HasChunks(hashes hash.HashSet) (present, missing hash.HashSet) {
+	rest := hash.HashSet{}
+	present, missing = hash.HashSet{}, hash.HashSet{}
+	for h := range hashes {
+		if _, ok := ms.pending[h]; ok {
+			present.Insert(h)
+		} else {
+			rest.Insert(h)
+		}
+	}
+
+	storagePresent, storageMissing := ms.storage.HasChunks(rest)
+	for h := range storagePresent {
+		present.Insert(h)
+	}
+	for h := range storageMissing {
+		missing.Insert(h)
+	}
+	return
+}
+
+// Iterate calls cb with every chunk visible to ms -- first the pending set,
+// then ms.storage -- stopping early if cb returns true.
+func (ms *privateStoreView) Iterate(cb func(h hash.Hash, c Chunk) bool) error {
+	for h, c := range ms.pending {
+		if cb(h, c) {
+			return nil
+		}
+	}
+	return ms.storage.Iterate(func(h hash.Hash, c Chunk) bool {
+		if _, ok := ms.pending[h]; ok {
+			return false
+		}
+		return cb(h, c)
+	})
+}
+
+func (ms *privateStoreView) Version() string {
+	return constants.NomsVersion
+}
+
+// Put stages c for the next Flush/Commit. If ms.storage.VerifyOnWrite is
+// set, it returns a VerificationError instead of staging c when c's content
+// doesn't hash to c.Hash().
+func (ms *privateStoreView) Put(c Chunk) error {
+	if ms.storage.VerifyOnWrite {
+		if err := verifyChunk(c); err != nil {
+			return err
+		}
+	}
+	if ms.pending == nil {
+		ms.pending = map[hash.Hash]Chunk{}
+	}
+	ms.pending[c.Hash()] = c
+	return nil
+}
+
+// PutMany stages each of chunks in order, stopping at (and returning) the
+// first error Put returns; chunks before the failing one remain staged.
+func (ms *privateStoreView) PutMany(chunks []Chunk) error {
+	for _, c := range chunks {
+		if err := ms.Put(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ms *privateStoreView) Len() int {
+	return len(ms.pending) + ms.storage.Len()
+}
+
+// Flush pushes ms's pending set into its backing storage. See
+// MemoryStoreView.Flush for why a PutAll failure here panics instead of
+// returning an error.
+func (ms *privateStoreView) Flush() {
+	d.PanicIfError(ms.storage.PutAll(ms.pending))
+	ms.pending = nil
+}
+
+func (ms *privateStoreView) Rebase() {
+	ms.rootHash = ms.storage.Root()
+}
+
+func (ms *privateStoreView) Root() hash.Hash {
+	return ms.rootHash
+}
+
+func (ms *privateStoreView) Commit(current, last hash.Hash) bool {
+	if last != ms.rootHash {
+		return false
+	}
+	d.PanicIfError(ms.storage.PutAll(ms.pending))
+	ms.pending = nil
+
+	success := ms.storage.UpdateRoot(current, last)
+	if success {
+		ms.rootHash = current
+	}
+	return success
+}
+
+func (ms *privateStoreView) Close() error {
+	return nil
+}
@@ -0,0 +1,74 @@
+// Copyright 2016 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package chunks
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/attic-labs/noms/go/hash"
+)
+
+func TestPrivateViewSeesPendingBeforeFlush(t *testing.T) {
+	storage := &MemoryStorage{}
+	view := storage.NewPrivateView()
+
+	c := NewChunk([]byte("private-view"))
+	if err := view.Put(c); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	if !view.Has(c.Hash()) {
+		t.Fatalf("expected Has to see the pending chunk before Flush")
+	}
+	got := view.Get(c.Hash())
+	if got.IsEmpty() || !bytes.Equal(got.Data(), c.Data()) {
+		t.Fatalf("Get did not return the pending chunk")
+	}
+	if storage.Has(c.Hash()) {
+		t.Fatalf("storage should not see the chunk before Flush")
+	}
+
+	view.Flush()
+
+	if !storage.Has(c.Hash()) {
+		t.Fatalf("expected storage to see the chunk after Flush")
+	}
+}
+
+func TestPrivateViewCommit(t *testing.T) {
+	storage := &MemoryStorage{}
+	view := storage.NewPrivateView()
+
+	c := NewChunk([]byte("commit-me"))
+	if err := view.Put(c); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	if !view.Commit(c.Hash(), hash.Hash{}) {
+		t.Fatalf("Commit failed")
+	}
+	if storage.Root() != c.Hash() {
+		t.Fatalf("root not updated by Commit")
+	}
+	if !storage.Has(c.Hash()) {
+		t.Fatalf("chunk not flushed by Commit")
+	}
+}
+
+func TestPrivateViewCommitRejectsStaleLast(t *testing.T) {
+	storage := &MemoryStorage{}
+	view := storage.NewPrivateView()
+
+	c := NewChunk([]byte("commit-me"))
+	if err := view.Put(c); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	stale := NewChunk([]byte("not-the-current-root")).Hash()
+	if view.Commit(c.Hash(), stale) {
+		t.Fatalf("Commit should fail against a stale last root")
+	}
+}
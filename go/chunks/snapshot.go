@@ -0,0 +1,199 @@
+// Copyright 2016 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package chunks
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/attic-labs/noms/go/hash"
+)
+
+// Frame type tags used by the snapshot stream format. A snapshot is an
+// ordered sequence of these self-describing, checksummed frames; a stream
+// always ends with exactly one root frame.
+const (
+	snapshotFrameChunks byte = 1
+	snapshotFrameRoot   byte = 2
+)
+
+// Snapshot serializes the entire ground-truth state of ms -- every chunk in
+// ms.data plus the current root -- to w as an ordered stream of framed
+// segments. Each frame has the form:
+//
+//	[4-byte big-endian length | sha256(payload) | payload]
+//
+// where payload is a chunk-batch frame, holding a concatenation of
+// [hashLen|hash|chunkLen|chunkBytes] records packed up to chunkBytes, or the
+// terminal root frame. Chunk-batch frames are independent of one another, so
+// a consumer can verify and apply each one on its own. RestoreMemoryStorage
+// verifies and applies them one at a time, in stream order; it does not
+// process frames in parallel.
+func (ms *MemoryStorage) Snapshot(w io.Writer, chunkBytes int) error {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	buf := make([]byte, 0, chunkBytes)
+	flush := func() error {
+		if len(buf) == 0 {
+			return nil
+		}
+		err := writeSnapshotFrame(w, snapshotFrameChunks, buf)
+		buf = buf[:0]
+		return err
+	}
+
+	for h, c := range ms.data {
+		rec := encodeChunkRecord(h, c)
+		if len(buf) > 0 && len(buf)+len(rec) > chunkBytes {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		buf = append(buf, rec...)
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	return writeSnapshotFrame(w, snapshotFrameRoot, ms.rootHash.DigestSlice())
+}
+
+// RestoreMemoryStorage reconstructs a MemoryStorage from a stream previously
+// written by (*MemoryStorage).Snapshot. Every frame's checksum is verified
+// before its contents are applied: chunk-batch frames are streamed straight
+// into PutAll as they're read, and the root is only ever set once the
+// terminal root frame has itself been validated, so a truncated or corrupt
+// stream can never leave the returned store with a root that outruns its
+// chunks.
+func RestoreMemoryStorage(r io.Reader) (*MemoryStorage, error) {
+	ms := &MemoryStorage{}
+
+	for {
+		frameType, payload, err := readSnapshotFrame(r)
+		if err == io.EOF {
+			return nil, fmt.Errorf("chunks: truncated snapshot, missing root frame")
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch frameType {
+		case snapshotFrameChunks:
+			chunks, err := decodeChunkRecords(payload)
+			if err != nil {
+				return nil, err
+			}
+			if err := ms.PutAll(chunks); err != nil {
+				return nil, err
+			}
+
+		case snapshotFrameRoot:
+			if len(payload) != hash.ByteLen {
+				return nil, fmt.Errorf("chunks: malformed root frame")
+			}
+			ms.rootHash = hash.New(payload)
+			return ms, nil
+
+		default:
+			return nil, fmt.Errorf("chunks: unrecognized snapshot frame type %d", frameType)
+		}
+	}
+}
+
+func encodeChunkRecord(h hash.Hash, c Chunk) []byte {
+	digest := h.DigestSlice()
+	data := c.Data()
+
+	rec := make([]byte, 0, 1+len(digest)+4+len(data))
+	rec = append(rec, byte(len(digest)))
+	rec = append(rec, digest...)
+	rec = appendSnapshotUint32(rec, uint32(len(data)))
+	rec = append(rec, data...)
+	return rec
+}
+
+func decodeChunkRecords(payload []byte) (map[hash.Hash]Chunk, error) {
+	chunks := map[hash.Hash]Chunk{}
+
+	for len(payload) > 0 {
+		if len(payload) < 1 {
+			return nil, fmt.Errorf("chunks: truncated chunk record")
+		}
+		hashLen := int(payload[0])
+		payload = payload[1:]
+		if len(payload) < hashLen+4 {
+			return nil, fmt.Errorf("chunks: truncated chunk record")
+		}
+
+		h := hash.New(payload[:hashLen])
+		payload = payload[hashLen:]
+
+		chunkLen := int(binary.BigEndian.Uint32(payload[:4]))
+		payload = payload[4:]
+		if len(payload) < chunkLen {
+			return nil, fmt.Errorf("chunks: truncated chunk record")
+		}
+
+		chunks[h] = NewChunk(payload[:chunkLen])
+		payload = payload[chunkLen:]
+	}
+
+	return chunks, nil
+}
+
+func writeSnapshotFrame(w io.Writer, frameType byte, body []byte) error {
+	payload := make([]byte, 0, 1+len(body))
+	payload = append(payload, frameType)
+	payload = append(payload, body...)
+
+	sum := sha256.Sum256(payload)
+
+	var header [4]byte
+	binary.BigEndian.PutUint32(header[:], uint32(len(payload)))
+
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+	if _, err := w.Write(sum[:]); err != nil {
+		return err
+	}
+	_, err := w.Write(payload)
+	return err
+}
+
+func readSnapshotFrame(r io.Reader) (byte, []byte, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	length := binary.BigEndian.Uint32(header[:])
+
+	var sum [sha256.Size]byte
+	if _, err := io.ReadFull(r, sum[:]); err != nil {
+		return 0, nil, err
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	if len(payload) == 0 {
+		return 0, nil, fmt.Errorf("chunks: empty snapshot frame")
+	}
+	if sha256.Sum256(payload) != sum {
+		return 0, nil, fmt.Errorf("chunks: snapshot frame failed checksum verification")
+	}
+
+	return payload[0], payload[1:], nil
+}
+
+func appendSnapshotUint32(b []byte, v uint32) []byte {
+	var tmp [4]byte
+	binary.BigEndian.PutUint32(tmp[:], v)
+	return append(b, tmp[:]...)
+}
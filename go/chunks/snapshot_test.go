@@ -0,0 +1,93 @@
+// Copyright 2016 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package chunks
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/attic-labs/noms/go/hash"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	storage := &MemoryStorage{}
+	cs := []Chunk{
+		NewChunk([]byte("chunk-a")),
+		NewChunk([]byte("chunk-b")),
+		NewChunk([]byte("chunk-c")),
+	}
+	pending := map[hash.Hash]Chunk{}
+	for _, c := range cs {
+		pending[c.Hash()] = c
+	}
+	if err := storage.PutAll(pending); err != nil {
+		t.Fatalf("PutAll: %v", err)
+	}
+	if !storage.UpdateRoot(cs[0].Hash(), hash.Hash{}) {
+		t.Fatalf("UpdateRoot failed")
+	}
+
+	var buf bytes.Buffer
+	// A tiny chunkBytes budget forces multiple chunk-batch frames, exercising
+	// the framing logic rather than just a single all-in-one frame.
+	if err := storage.Snapshot(&buf, 8); err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+
+	restored, err := RestoreMemoryStorage(&buf)
+	if err != nil {
+		t.Fatalf("RestoreMemoryStorage: %v", err)
+	}
+	if restored.Root() != storage.Root() {
+		t.Fatalf("root mismatch: got %s, want %s", restored.Root(), storage.Root())
+	}
+	if restored.Len() != storage.Len() {
+		t.Fatalf("chunk count mismatch: got %d, want %d", restored.Len(), storage.Len())
+	}
+	for _, c := range cs {
+		got := restored.Get(c.Hash())
+		if got.IsEmpty() || !bytes.Equal(got.Data(), c.Data()) {
+			t.Fatalf("chunk %s not restored correctly", c.Hash())
+		}
+	}
+}
+
+func TestSnapshotDetectsCorruption(t *testing.T) {
+	storage := &MemoryStorage{}
+	c := NewChunk([]byte("hello"))
+	if err := storage.PutAll(map[hash.Hash]Chunk{c.Hash(): c}); err != nil {
+		t.Fatalf("PutAll: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := storage.Snapshot(&buf, 1<<20); err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+
+	corrupted := buf.Bytes()
+	corrupted[len(corrupted)-1] ^= 0xFF
+
+	if _, err := RestoreMemoryStorage(bytes.NewReader(corrupted)); err == nil {
+		t.Fatalf("expected a checksum failure, got nil error")
+	}
+}
+
+func TestSnapshotDetectsTruncation(t *testing.T) {
+	storage := &MemoryStorage{}
+	c := NewChunk([]byte("hello"))
+	if err := storage.PutAll(map[hash.Hash]Chunk{c.Hash(): c}); err != nil {
+		t.Fatalf("PutAll: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := storage.Snapshot(&buf, 1<<20); err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+
+	truncated := buf.Bytes()[:buf.Len()-4]
+	if _, err := RestoreMemoryStorage(bytes.NewReader(truncated)); err == nil {
+		t.Fatalf("expected an error for a truncated stream, got nil")
+	}
+}
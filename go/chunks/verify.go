@@ -0,0 +1,47 @@
+// Copyright 2016 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package chunks
+
+import (
+	"fmt"
+
+	"github.com/attic-labs/noms/go/d"
+	"github.com/attic-labs/noms/go/hash"
+)
+
+// VerificationError is returned by Put/PutMany/PutAll, and panicked by Get,
+// when the corresponding Verify* flag is set and a chunk's content doesn't
+// hash to the value it's claimed or stored under. It's typed so that a
+// caller running with verification on can inspect exactly which chunk
+// failed, rather than matching on a string.
+type VerificationError struct {
+	Claimed hash.Hash
+	Actual  hash.Hash
+}
+
+func (e VerificationError) Error() string {
+	return fmt.Sprintf("chunks: content hashes to %s, not claimed hash %s", e.Actual, e.Claimed)
+}
+
+// verifyChunk returns a VerificationError if c's content doesn't hash to
+// c.Hash(). Put trusts c.Hash() by default; this is the opt-in check that
+// catches a buggy or corrupting producer before its chunk poisons the store.
+func verifyChunk(c Chunk) error {
+	if claimed, actual := c.Hash(), hash.Of(c.Data()); claimed != actual {
+		return VerificationError{Claimed: claimed, Actual: actual}
+	}
+	return nil
+}
+
+// PutWithoutError adapts cs.Put for the rare caller that holds a Put
+// func value typed as func(Chunk) (predating this package's move to a
+// ChunkStore.Put that returns error) and has no way to propagate a returned
+// error at that call site. It panics via d.PanicIfError instead, which
+// wraps the error before panicking, consistent with this package's existing
+// convention for invariant violations. New callers should call cs.Put
+// directly and handle the error.
+func PutWithoutError(cs ChunkStore, c Chunk) {
+	d.PanicIfError(cs.Put(c))
+}
@@ -0,0 +1,89 @@
+// Copyright 2016 Attic Labs, Inc. All rights reserved.
+// Licensed under the Apache License, version 2.0:
+// http://www.apache.org/licenses/LICENSE-2.0
+
+package chunks
+
+import (
+	"testing"
+
+	"github.com/attic-labs/noms/go/hash"
+)
+
+func corruptChunk() Chunk {
+	return NewChunkWithHash(hash.Of([]byte("not-the-real-content")), []byte("actual-data"))
+}
+
+func TestVerifyOnWritePutRejectsCorruption(t *testing.T) {
+	storage := &MemoryStorage{VerifyOnWrite: true}
+	view := storage.NewView()
+
+	bad := corruptChunk()
+	err := view.Put(bad)
+	if err == nil {
+		t.Fatalf("expected a VerificationError, got nil")
+	}
+	if _, ok := err.(VerificationError); !ok {
+		t.Fatalf("expected a VerificationError, got %T: %v", err, err)
+	}
+	if view.Has(bad.Hash()) {
+		t.Fatalf("corrupt chunk should not have been staged")
+	}
+}
+
+func TestVerifyOnWriteOffByDefault(t *testing.T) {
+	storage := &MemoryStorage{}
+	view := storage.NewView()
+
+	bad := corruptChunk()
+	if err := view.Put(bad); err != nil {
+		t.Fatalf("Put should succeed with VerifyOnWrite off, got %v", err)
+	}
+}
+
+func TestVerifyOnWritePutAllRejectsCorruption(t *testing.T) {
+	storage := &MemoryStorage{VerifyOnWrite: true}
+	bad := corruptChunk()
+
+	err := storage.PutAll(map[hash.Hash]Chunk{bad.Hash(): bad})
+	if err == nil {
+		t.Fatalf("expected a VerificationError, got nil")
+	}
+	if storage.Has(bad.Hash()) {
+		t.Fatalf("corrupt chunk should not have been added")
+	}
+}
+
+func TestVerifyOnReadPanicsOnCorruption(t *testing.T) {
+	// VerifyOnWrite is independently gated, so write the corrupt chunk in
+	// with it off, then read it back with VerifyOnRead on.
+	storage := &MemoryStorage{VerifyOnRead: true}
+	bad := corruptChunk()
+	if err := storage.PutAll(map[hash.Hash]Chunk{bad.Hash(): bad}); err != nil {
+		t.Fatalf("PutAll: %v", err)
+	}
+
+	defer func() {
+		r := recover()
+		if r == nil {
+			t.Fatalf("expected Get to panic on a corrupt chunk")
+		}
+		if _, ok := r.(VerificationError); !ok {
+			t.Fatalf("expected panic value to be a VerificationError, got %T: %v", r, r)
+		}
+	}()
+	storage.Get(bad.Hash())
+}
+
+func TestPutWithoutErrorPanicsOnVerificationFailure(t *testing.T) {
+	storage := &MemoryStorage{VerifyOnWrite: true}
+	view := storage.NewView()
+	bad := corruptChunk()
+
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected PutWithoutError to panic on verification failure")
+		}
+	}()
+	PutWithoutError(view, bad)
+}